@@ -0,0 +1,91 @@
+package twofactor
+
+import (
+	"crypto"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSteamTOTPTokenFormat(t *testing.T) {
+	otp, err := NewSteamTOTP("info@sec51.com", "Sec51")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := otp.OTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(token) != 5 {
+		t.Fatalf("Expected a 5 character Steam Guard code, got %q", token)
+	}
+
+	for _, c := range token {
+		if !strings.ContainsRune(steamAlphabet, c) {
+			t.Errorf("Token %q contains a character outside the Steam alphabet", token)
+		}
+	}
+
+	if err := otp.Validate(token); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSteamTOTPURLCarriesEncoder(t *testing.T) {
+	otp, err := NewSteamTOTP("info@sec51.com", "Sec51")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uri, err := otp.url()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.Query().Get("encoder") != "steam" {
+		t.Errorf("Expected encoder=steam in a Steam Guard otpauth URI, got %s", uri)
+	}
+}
+
+func TestNewTOTPWithAlphabetRejectsTooSmallAlphabet(t *testing.T) {
+	if _, err := NewTOTPWithAlphabet("info@sec51.com", "Sec51", crypto.SHA1, 5, "A"); err == nil {
+		t.Fatal("Expected an error when the alphabet has fewer than 2 characters")
+	}
+}
+
+func TestAlphabetRoundtripsThroughSerialization(t *testing.T) {
+	otp, err := NewSteamTOTP("info@sec51.com", "Sec51")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := otp.ToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := TOTPFromBytes(data, otp.issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.alphabet != otp.alphabet {
+		t.Error("Deserialized alphabet differs from the original Totp")
+	}
+
+	token, err := otp.OTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := restored.Validate(token); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,81 @@
+package twofactor
+
+import (
+	"crypto"
+	"testing"
+	"time"
+)
+
+func TestBackoffPolicyRateLimitsAfterMaxFailures(t *testing.T) {
+	otp, err := NewTOTP("info@sec51.com", "Sec51", crypto.SHA1, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otp.SetBackoffPolicy(time.Second, time.Minute, 2)
+
+	for i := 0; i < 2; i++ {
+		if err := otp.Validate("000000"); err == nil {
+			t.Fatal("Expected an error validating a wrong token")
+		}
+	}
+
+	err = otp.Validate("000000")
+	rateLimited, ok := err.(ErrRateLimited)
+	if !ok {
+		t.Fatalf("Expected ErrRateLimited after %d consecutive failures, got %v (%T)", otp.maxFailures, err, err)
+	}
+
+	if rateLimited.Remaining <= 0 {
+		t.Error("Expected a positive remaining wait duration")
+	}
+}
+
+func TestBackoffPolicyUnlocksAfterWaitElapses(t *testing.T) {
+	otp, err := NewTOTP("info@sec51.com", "Sec51", crypto.SHA1, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otp.SetBackoffPolicy(time.Second, time.Second, 1)
+
+	if err := otp.Validate("000000"); err == nil {
+		t.Fatal("Expected an error validating a wrong token")
+	}
+
+	// push the last verification time far enough into the past that the
+	// (capped at 1s + up to 1s jitter) backoff window has definitely elapsed
+	otp.lastVerificationTime = time.Now().UTC().Add(-10 * time.Second)
+
+	token, err := otp.OTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := otp.Validate(token); err != nil {
+		t.Fatalf("Expected the backoff window to have elapsed, got %v", err)
+	}
+}
+
+func TestBackoffPolicyPersistsAcrossSerialization(t *testing.T) {
+	otp, err := NewTOTP("info@sec51.com", "Sec51", crypto.SHA1, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otp.SetBackoffPolicy(2*time.Second, 5*time.Minute, 7)
+
+	data, err := otp.ToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := TOTPFromBytes(data, otp.issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.backoffBase != otp.backoffBase || restored.backoffCap != otp.backoffCap || restored.maxFailures != otp.maxFailures {
+		t.Error("Deserialized backoff policy differs from the original Totp")
+	}
+}
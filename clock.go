@@ -0,0 +1,198 @@
+package twofactor
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+)
+
+// Clock supplies the current time to a Totp. Externalizing it from the
+// package default (time.Now().UTC()) gives tests a seam for deterministic
+// time, and gives servers a seam for NTP-synchronized sources - see
+// NetworkClock.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the package default Clock, backed by the system clock.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+// defaultClock is used by NewTOTP when no WithClock option is supplied.
+var defaultClock Clock = systemClock{}
+
+// Option configures a Totp at construction time.
+type Option func(*Totp)
+
+// WithClock overrides the Clock a Totp uses to read the current time,
+// instead of the package default system clock.
+func WithClock(clock Clock) Option {
+	return func(t *Totp) {
+		t.clock = clock
+	}
+}
+
+// ntpEpochOffset is the number of seconds between the NTP epoch
+// (1900-01-01) and the Unix epoch (1970-01-01).
+const ntpEpochOffset = 2208988800
+
+// ntpPacket is the wire format of an SNTP v3/v4 packet (RFC 4330), minus
+// the optional authenticator trailer.
+type ntpPacket struct {
+	Settings       uint8
+	Stratum        uint8
+	Poll           int8
+	Precision      int8
+	RootDelay      uint32
+	RootDispersion uint32
+	ReferenceID    uint32
+	RefTimeSec     uint32
+	RefTimeFrac    uint32
+	OrigTimeSec    uint32
+	OrigTimeFrac   uint32
+	RxTimeSec      uint32
+	RxTimeFrac     uint32
+	TxTimeSec      uint32
+	TxTimeFrac     uint32
+}
+
+// NetworkClock is a Clock that periodically queries an NTP server and keeps
+// a running clientOffset between the system clock and the server's clock,
+// so that Totp.Validate can tell genuine server clock drift apart from the
+// ordinary +/-1 step client skew it already tolerates.
+type NetworkClock struct {
+	server   string
+	interval time.Duration
+
+	mu     sync.RWMutex
+	offset time.Duration
+
+	stop chan struct{}
+}
+
+// NewNetworkClock creates a NetworkClock that queries server (host:port,
+// defaulting to port 123 if no port is given) every refreshInterval. The
+// first sync happens synchronously so Now() never observes an un-synced
+// offset; if it fails, the offset starts at zero and is corrected on the
+// next periodic attempt.
+func NewNetworkClock(server string, refreshInterval time.Duration) *NetworkClock {
+	c := &NetworkClock{
+		server:   server,
+		interval: refreshInterval,
+		stop:     make(chan struct{}),
+	}
+
+	c.sync()
+	go c.run()
+
+	return c
+}
+
+// Now returns the system time adjusted by the last known offset to the NTP
+// server.
+func (c *NetworkClock) Now() time.Time {
+	c.mu.RLock()
+	offset := c.offset
+	c.mu.RUnlock()
+
+	return time.Now().UTC().Add(offset)
+}
+
+// Offset returns the current system-to-server clock offset, so a caller can
+// persist it and seed it back with SetOffset after a restart rather than
+// waiting for the next sync.
+func (c *NetworkClock) Offset() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.offset
+}
+
+// SetOffset seeds the clock offset, typically with a value previously read
+// from Offset and persisted across a restart.
+func (c *NetworkClock) SetOffset(offset time.Duration) {
+	c.mu.Lock()
+	c.offset = offset
+	c.mu.Unlock()
+}
+
+// Close stops the background refresh. The NetworkClock remains usable
+// afterwards, serving its last known offset.
+func (c *NetworkClock) Close() {
+	close(c.stop)
+}
+
+func (c *NetworkClock) run() {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.sync()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+func (c *NetworkClock) sync() {
+	offset, err := queryNTPOffset(c.server)
+	if err != nil {
+		// Keep the last known offset; a transient NTP failure shouldn't make
+		// Now() jump back to the unsynced system clock.
+		return
+	}
+
+	c.mu.Lock()
+	c.offset = offset
+	c.mu.Unlock()
+}
+
+// queryNTPOffset runs a single SNTP round-trip against server and returns
+// the clock offset (server time minus local time) using the standard NTP
+// offset formula: ((t2-t1) + (t3-t4)) / 2, where t1/t4 are local send/
+// receive times and t2/t3 are the server's receive/transmit times.
+func queryNTPOffset(server string) (time.Duration, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+
+	conn, err := net.DialTimeout("udp", server, 5*time.Second)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		return 0, err
+	}
+
+	request := ntpPacket{Settings: 0x1B} // leap=0, version=3, mode=3 (client)
+	t1 := time.Now().UTC()
+	if err := binary.Write(conn, binary.BigEndian, &request); err != nil {
+		return 0, err
+	}
+
+	var response ntpPacket
+	if err := binary.Read(conn, binary.BigEndian, &response); err != nil {
+		return 0, err
+	}
+	t4 := time.Now().UTC()
+
+	t2 := ntpToTime(response.RxTimeSec, response.RxTimeFrac)
+	t3 := ntpToTime(response.TxTimeSec, response.TxTimeFrac)
+
+	return (t2.Sub(t1) + t3.Sub(t4)) / 2, nil
+}
+
+// ntpToTime converts an NTP (seconds, fraction) timestamp pair into a Go
+// time.Time in the Unix epoch.
+func ntpToTime(seconds, fraction uint32) time.Time {
+	unixSeconds := int64(seconds) - ntpEpochOffset
+	nanos := int64(float64(fraction) * (1e9 / 4294967296.0))
+	return time.Unix(unixSeconds, nanos).UTC()
+}
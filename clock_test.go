@@ -0,0 +1,85 @@
+package twofactor
+
+import (
+	"crypto"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestWithClockDrivesOTPGeneration(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1111111100, 0).UTC()}
+
+	otp, err := NewTOTP("info@sec51.com", "Sec51", crypto.SHA1, 8, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := otp.OTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// advancing the fake clock within the same 30 second step must not
+	// change the token
+	clock.now = clock.now.Add(5 * time.Second)
+	second, err := otp.OTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first != second {
+		t.Errorf("Expected the same token within a time step, got %q and %q", first, second)
+	}
+
+	// crossing into the next time step must change the token
+	clock.now = clock.now.Add(30 * time.Second)
+	third, err := otp.OTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if third == second {
+		t.Error("Expected a different token after crossing into the next time step")
+	}
+}
+
+func TestWithClockIsDeterministicForValidate(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1234567890, 0).UTC()}
+
+	otp, err := NewTOTP("info@sec51.com", "Sec51", crypto.SHA256, 6, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := otp.OTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := otp.Validate(token); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNetworkClockOffset(t *testing.T) {
+	clock := &NetworkClock{stop: make(chan struct{})}
+	clock.SetOffset(2 * time.Second)
+
+	if clock.Offset() != 2*time.Second {
+		t.Errorf("Expected Offset to return the seeded value, got %s", clock.Offset())
+	}
+
+	before := time.Now().UTC()
+	now := clock.Now()
+	if now.Before(before.Add(2 * time.Second)) {
+		t.Error("Expected Now() to be adjusted forward by the seeded offset")
+	}
+}
@@ -0,0 +1,35 @@
+package twofactor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNilOtp is returned when the methods of an uninitialized Totp/Hotp are invoked.
+var ErrNilOtp = errors.New("The OTP reference is nil")
+
+// ErrBadInput is returned when the parameters passed to a constructor are invalid.
+var ErrBadInput = errors.New("One of the supplied parameters is invalid")
+
+// ErrUsedOTP is returned when a token has already been used for verification.
+var ErrUsedOTP = errors.New("The OTP has already been used")
+
+// ErrValidation is returned when the verification of a token fails.
+var ErrValidation = errors.New("Failed to validate the OTP token")
+
+// ErrNotFound is returned by a Store when no Totp is registered for a given
+// issuer/account pair.
+var ErrNotFound = errors.New("No OTP found for the given issuer/account")
+
+// ErrRateLimited is returned by Validate when too many consecutive
+// verification failures have occurred and the backoff policy's wait has not
+// elapsed yet. Remaining is how much longer the caller must wait before
+// trying again, e.g. to set a Retry-After header.
+type ErrRateLimited struct {
+	Remaining time.Duration
+}
+
+func (e ErrRateLimited) Error() string {
+	return fmt.Sprintf("too many verification failures, retry after %s", e.Remaining)
+}
@@ -0,0 +1,233 @@
+package twofactor
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/gob"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/sec51/convert/bigendian"
+	"github.com/sec51/cryptoengine"
+)
+
+// Hotp represents an RFC 4226 counter-based one-time password generator/
+// validator. Unlike Totp it has no notion of time: the server and client
+// counters must be kept in sync, which Validate helps with via a
+// resynchronization lookahead window.
+type Hotp struct {
+	key          []byte
+	counter      uint64
+	digits       int
+	issuer       string
+	account      string
+	hashFunction crypto.Hash
+
+	// validated and lastValidatedCounter track the server-side validation
+	// watermark, independent of counter (which only reflects how many
+	// tokens OTP has generated): Validate must accept a token it has not
+	// already accepted, even one OTP already generated, so it searches
+	// forward from the last counter it confirmed rather than from counter.
+	validated            bool
+	lastValidatedCounter uint64
+}
+
+// NewHOTP creates a new Hotp for the given account/issuer pair, generating a
+// fresh random shared secret and starting its counter at zero.
+func NewHOTP(account, issuer string, hashFunction crypto.Hash, digits int) (*Hotp, error) {
+	if len(account) == 0 || len(issuer) == 0 || digits <= 0 {
+		return nil, ErrBadInput
+	}
+
+	if !hashFunction.Available() {
+		return nil, ErrBadInput
+	}
+
+	key, err := randomKey(keySize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Hotp{
+		key:          key,
+		issuer:       issuer,
+		account:      account,
+		digits:       digits,
+		hashFunction: hashFunction,
+	}, nil
+}
+
+// OTP returns the token for the current counter value and advances the
+// counter, since an HOTP generator must never emit the same counter twice.
+func (h *Hotp) OTP() (string, error) {
+	if h == nil || h.key == nil {
+		return "", ErrNilOtp
+	}
+
+	token := hotpToken(h, h.counter)
+	h.counter++
+	return token, nil
+}
+
+// Validate checks token against the next counter this Hotp has not already
+// confirmed and, to tolerate the client and server counters drifting apart,
+// the following lookAhead counters. On a match, both the generation counter
+// and the validation watermark are resynchronized to one past the counter
+// that matched, so neither that token nor any earlier one can be replayed.
+func (h *Hotp) Validate(token string, lookAhead int) error {
+	if h == nil || h.key == nil {
+		return ErrNilOtp
+	}
+
+	if lookAhead < 0 {
+		return ErrBadInput
+	}
+
+	start := uint64(0)
+	if h.validated {
+		start = h.lastValidatedCounter + 1
+	}
+
+	for i := 0; i <= lookAhead; i++ {
+		counter := start + uint64(i)
+		candidate := hotpToken(h, counter)
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+			h.validated = true
+			h.lastValidatedCounter = counter
+			h.counter = counter + 1
+			return nil
+		}
+	}
+
+	return ErrValidation
+}
+
+// hotpToken computes the RFC 4226 token for a given counter value, sharing
+// the dynamic truncation primitive used by Totp.
+func hotpToken(h *Hotp, counter uint64) string {
+	counterBytes := bigendian.ToUint64(counter)
+	hm := hmac.New(h.hashFunction.New, h.key)
+	return calculateToken(counterBytes[:], h.digits, hm)
+}
+
+// label returns the otpauth label for this Hotp: the issuer and account,
+// each percent-encoded and joined by a literal colon.
+func (h *Hotp) label() string {
+	return otpLabel(h.issuer, h.account)
+}
+
+// url returns the otpauth:// provisioning URI for this Hotp, suitable for
+// rendering as a QR code in an authenticator app.
+func (h *Hotp) url() (string, error) {
+	if h == nil || h.key == nil || len(h.issuer) == 0 || len(h.account) == 0 {
+		return "", ErrNilOtp
+	}
+
+	u := url.URL{
+		Scheme: "otpauth",
+		Host:   "hotp",
+		Path:   "/" + h.label(),
+	}
+
+	q := u.Query()
+	q.Set("secret", base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(h.key))
+	q.Set("issuer", h.issuer)
+	q.Set("algorithm", hashAlgorithmName(h.hashFunction))
+	q.Set("digits", strconv.Itoa(h.digits))
+	q.Set("counter", strconv.FormatUint(h.counter, 10))
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// hotpGobData is the on-the-wire representation of a Hotp, encrypted with
+// cryptoengine before being returned by ToBytes.
+type hotpGobData struct {
+	Key                  []byte
+	Counter              uint64
+	Digits               int
+	Issuer               string
+	Account              string
+	HashFunction         crypto.Hash
+	Validated            bool
+	LastValidatedCounter uint64
+}
+
+// ToBytes serializes and encrypts the Hotp, keyed to its issuer, so it can
+// be persisted by the caller and later restored with HOTPFromBytes.
+func (h *Hotp) ToBytes() ([]byte, error) {
+	if h == nil || h.key == nil {
+		return nil, ErrNilOtp
+	}
+
+	var buf bytes.Buffer
+	data := hotpGobData{
+		Key:                  h.key,
+		Counter:              h.counter,
+		Digits:               h.digits,
+		Issuer:               h.issuer,
+		Account:              h.account,
+		HashFunction:         h.hashFunction,
+		Validated:            h.validated,
+		LastValidatedCounter: h.lastValidatedCounter,
+	}
+
+	if err := gob.NewEncoder(&buf).Encode(data); err != nil {
+		return nil, err
+	}
+
+	engine, err := cryptoengine.InitCryptoEngine(h.issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	plainMessage, err := cryptoengine.NewMessage(buf.String(), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedMessage, err := engine.NewEncryptedMessage(plainMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	return encryptedMessage.ToBytes()
+}
+
+// HOTPFromBytes decrypts and deserializes a Hotp previously produced by
+// ToBytes. The issuer must match the one the Hotp was encrypted with.
+func HOTPFromBytes(encryptedData []byte, issuer string) (*Hotp, error) {
+	if len(issuer) == 0 {
+		return nil, ErrBadInput
+	}
+
+	engine, err := cryptoengine.InitCryptoEngine(issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	plainMessage, err := engine.Decrypt(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	var data hotpGobData
+	if err := gob.NewDecoder(strings.NewReader(plainMessage.Text)).Decode(&data); err != nil {
+		return nil, err
+	}
+
+	return &Hotp{
+		key:                  data.Key,
+		counter:              data.Counter,
+		digits:               data.Digits,
+		issuer:               data.Issuer,
+		account:              data.Account,
+		hashFunction:         data.HashFunction,
+		validated:            data.Validated,
+		lastValidatedCounter: data.LastValidatedCounter,
+	}, nil
+}
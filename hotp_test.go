@@ -0,0 +1,161 @@
+package twofactor
+
+import (
+	"crypto"
+	"net/url"
+	"testing"
+)
+
+func TestHOTPGeneratesDistinctTokensPerCounter(t *testing.T) {
+	otp, err := NewHOTP("info@sec51.com", "Sec51", crypto.SHA1, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := otp.OTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := otp.OTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first == second {
+		t.Error("Two consecutive HOTP tokens should differ, since the counter advances")
+	}
+}
+
+func TestHOTPValidate(t *testing.T) {
+	otp, err := NewHOTP("info@sec51.com", "Sec51", crypto.SHA256, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := otp.OTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := otp.Validate(token, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// the counter has already moved past the validated token: replaying it
+	// should fail even within the lookahead window
+	if err := otp.Validate(token, 10); err == nil {
+		t.Error("Validate should reject a replayed HOTP token")
+	}
+}
+
+func TestHOTPValidateResynchronizes(t *testing.T) {
+	otp, err := NewHOTP("info@sec51.com", "Sec51", crypto.SHA1, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// drop three tokens on the floor to simulate a client that is ahead
+	if _, err := otp.OTP(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := otp.OTP(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := otp.OTP(); err != nil {
+		t.Fatal(err)
+	}
+
+	laggingCounter := otp.counter
+	fourth := hotpToken(otp, laggingCounter)
+
+	if err := otp.Validate(fourth, 0); err == nil {
+		t.Error("Validate should not accept a counter outside the lookahead window")
+	}
+
+	if err := otp.Validate(fourth, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	if otp.counter != laggingCounter+1 {
+		t.Errorf("Expected counter to resynchronize to %d, got %d", laggingCounter+1, otp.counter)
+	}
+}
+
+func TestHOTPSerialization(t *testing.T) {
+	otp, err := NewHOTP("info@sec51.com", "Sec51", crypto.SHA512, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := otp.OTP(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := otp.ToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := HOTPFromBytes(data, otp.issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.counter != otp.counter {
+		t.Errorf("Deserialized counter differs from original: got %d, expected %d", restored.counter, otp.counter)
+	}
+
+	if restored.digits != otp.digits {
+		t.Error("Deserialized digits property differs from original HOTP")
+	}
+
+	if restored.account != otp.account {
+		t.Error("Deserialized account property differs from original HOTP")
+	}
+
+	if restored.label() != otp.label() {
+		t.Error("Deserialized label differs from original HOTP")
+	}
+}
+
+func TestHOTPLabelAndURL(t *testing.T) {
+	otp, err := NewHOTP("info@sec51.com", "Sec51", crypto.SHA1, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	label, err := url.QueryUnescape(otp.label())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if label != "Sec51:info@sec51.com" {
+		t.Error("Creation of HOTP label failed")
+	}
+
+	uri, err := otp.url()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.Scheme != "otpauth" || parsed.Host != "hotp" {
+		t.Errorf("Expected an otpauth://hotp/... URI, got %s", uri)
+	}
+
+	if parsed.Query().Get("counter") != "0" {
+		t.Errorf("Expected counter=0 in a freshly created HOTP URI, got %s", parsed.Query().Get("counter"))
+	}
+}
+
+func TestHOTPUninitialized(t *testing.T) {
+	otp := Hotp{}
+	if _, err := otp.url(); err == nil {
+		t.Fatal("Hotp is not properly initialized and the method did not catch it")
+	}
+}
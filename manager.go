@@ -0,0 +1,61 @@
+package twofactor
+
+import "sync"
+
+// Manager wraps a Store to provide an atomic load-validate-save cycle per
+// issuer/account, so that two concurrent Validate calls for the same user
+// cannot both observe (and accept) the same token, or both bump
+// totalVerificationFailures past what a single in-memory Totp would allow.
+type Manager struct {
+	store Store
+
+	locksMu sync.Mutex
+	locks   map[string]*sync.Mutex
+}
+
+// NewManager creates a Manager backed by store.
+func NewManager(store Store) *Manager {
+	return &Manager{
+		store: store,
+		locks: make(map[string]*sync.Mutex),
+	}
+}
+
+// lockFor returns the per issuer/account mutex, creating it on first use.
+func (m *Manager) lockFor(issuer, account string) *sync.Mutex {
+	key := storeKey(issuer, account)
+
+	m.locksMu.Lock()
+	defer m.locksMu.Unlock()
+
+	lock, ok := m.locks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		m.locks[key] = lock
+	}
+
+	return lock
+}
+
+// Validate loads the Totp for issuer/account, validates token against it,
+// and persists the updated counter/failure state back to the store before
+// returning - all while holding that account's lock, so a concurrent call
+// for the same account blocks until this one has saved its result.
+func (m *Manager) Validate(issuer, account, token string) error {
+	lock := m.lockFor(issuer, account)
+	lock.Lock()
+	defer lock.Unlock()
+
+	otp, err := m.store.Load(issuer, account)
+	if err != nil {
+		return err
+	}
+
+	validateErr := otp.Validate(token)
+
+	if err := m.store.Save(otp); err != nil {
+		return err
+	}
+
+	return validateErr
+}
@@ -0,0 +1,85 @@
+package twofactor
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+
+	"rsc.io/qr"
+)
+
+// defaultQRLevel is the error correction level used by QR when encoding an
+// enrollment URI: "M" recovers from ~15% damage, which is a reasonable
+// default for a code that is going to be displayed on a screen and scanned
+// once.
+const defaultQRLevel = qr.M
+
+// QR renders the otpauth:// provisioning URI for this Totp as a PNG QR code
+// of roughly size x size pixels, ready to be shown to the user for
+// enrollment in an authenticator app.
+func (t *Totp) QR(size int) ([]byte, error) {
+	uri, err := t.url()
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeURIAsQR(uri, defaultQRLevel, size)
+}
+
+// QR renders the otpauth:// provisioning URI for this Hotp as a PNG QR code
+// of roughly size x size pixels, ready to be shown to the user for
+// enrollment in an authenticator app.
+func (h *Hotp) QR(size int) ([]byte, error) {
+	uri, err := h.url()
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeURIAsQR(uri, defaultQRLevel, size)
+}
+
+// EncodeURIAsQR encodes an arbitrary otpauth:// (or any other) URI as a PNG
+// QR code at the given error correction level, for callers that built their
+// own URI rather than going through Totp.url/Hotp.url.
+func EncodeURIAsQR(uri string, level qr.Level) ([]byte, error) {
+	code, err := qr.Encode(uri, level)
+	if err != nil {
+		return nil, err
+	}
+
+	return code.PNG(), nil
+}
+
+// encodeURIAsQR is the shared implementation behind Totp.QR and Hotp.QR: it
+// encodes uri at level and scales the result to approximately size x size
+// pixels using nearest-neighbor scaling, so every module stays a crisp
+// block rather than being blurred.
+func encodeURIAsQR(uri string, level qr.Level, size int) ([]byte, error) {
+	code, err := qr.Encode(uri, level)
+	if err != nil {
+		return nil, err
+	}
+
+	if size <= 0 {
+		return code.PNG(), nil
+	}
+
+	src := code.Image()
+	dst := image.NewGray(image.Rect(0, 0, size, size))
+	srcBounds := src.Bounds()
+
+	for y := 0; y < size; y++ {
+		srcY := srcBounds.Min.Y + y*srcBounds.Dy()/size
+		for x := 0; x < size; x++ {
+			srcX := srcBounds.Min.X + x*srcBounds.Dx()/size
+			dst.Set(x, y, src.At(srcX, srcY))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
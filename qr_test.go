@@ -0,0 +1,69 @@
+package twofactor
+
+import (
+	"bytes"
+	"crypto"
+	"image/png"
+	"testing"
+
+	"rsc.io/qr"
+)
+
+func TestTotpQR(t *testing.T) {
+	otp, err := NewTOTP("info@sec51.com", "Sec51", crypto.SHA1, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := otp.QR(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bounds := img.Bounds()
+	if bounds.Dx() != 256 || bounds.Dy() != 256 {
+		t.Errorf("Expected a 256x256 QR code, got %dx%d", bounds.Dx(), bounds.Dy())
+	}
+}
+
+func TestHotpQR(t *testing.T) {
+	otp, err := NewHOTP("info@sec51.com", "Sec51", crypto.SHA1, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := otp.QR(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncodeURIAsQR(t *testing.T) {
+	otp, err := NewTOTP("info@sec51.com", "Sec51", crypto.SHA1, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uri, err := otp.url()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := EncodeURIAsQR(uri, qr.M)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+}
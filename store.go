@@ -0,0 +1,72 @@
+package twofactor
+
+import "sync"
+
+// Store persists Totp state across requests and processes, so a server can
+// keep many users' 2FA enrollments without having to thread an opaque blob
+// back and forth on every call.
+type Store interface {
+	// Load returns the Totp registered for issuer/account, or ErrNotFound
+	// if none exists.
+	Load(issuer, account string) (*Totp, error)
+
+	// Save persists otp, creating or overwriting the entry for its
+	// issuer/account.
+	Save(otp *Totp) error
+
+	// Delete removes the entry for issuer/account, if any.
+	Delete(issuer, account string) error
+}
+
+// storeKey builds the map/row key a Store implementation indexes by.
+func storeKey(issuer, account string) string {
+	return issuer + "\x00" + account
+}
+
+// MemoryStore is an in-process, in-memory Store. It is safe for concurrent
+// use and is the natural choice for a single-instance server or for tests;
+// restarting the process loses every enrollment.
+type MemoryStore struct {
+	mu   sync.Mutex
+	otps map[string]*Totp
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{otps: make(map[string]*Totp)}
+}
+
+// Load implements Store.
+func (s *MemoryStore) Load(issuer, account string) (*Totp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	otp, ok := s.otps[storeKey(issuer, account)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return otp, nil
+}
+
+// Save implements Store.
+func (s *MemoryStore) Save(otp *Totp) error {
+	if otp == nil || otp.key == nil {
+		return ErrNilOtp
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.otps[storeKey(otp.issuer, otp.account)] = otp
+	return nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(issuer, account string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.otps, storeKey(issuer, account))
+	return nil
+}
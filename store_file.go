@@ -0,0 +1,72 @@
+package twofactor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+)
+
+// FileStore is a Store backed by one file per enrollment on the local
+// filesystem, each holding the ToBytes/TOTPFromBytes encrypted blob for
+// that issuer/account. It suits single-server deployments that want
+// enrollments to survive a restart without standing up a database.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a FileStore rooted at dir, which must already exist.
+func NewFileStore(dir string) (*FileStore, error) {
+	if info, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, ErrBadInput
+	}
+
+	return &FileStore{dir: dir}, nil
+}
+
+// path returns the file an issuer/account pair is stored at: the directory
+// root, plus the hex-encoded SHA-256 of the store key, so that arbitrary
+// issuer/account strings never have to be sanitized into a valid filename.
+func (s *FileStore) path(issuer, account string) string {
+	sum := sha256.Sum256([]byte(storeKey(issuer, account)))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".otp")
+}
+
+// Load implements Store.
+func (s *FileStore) Load(issuer, account string) (*Totp, error) {
+	data, err := os.ReadFile(s.path(issuer, account))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return TOTPFromBytes(data, issuer)
+}
+
+// Save implements Store.
+func (s *FileStore) Save(otp *Totp) error {
+	if otp == nil || otp.key == nil {
+		return ErrNilOtp
+	}
+
+	data, err := otp.ToBytes()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.path(otp.issuer, otp.account), data, 0600)
+}
+
+// Delete implements Store.
+func (s *FileStore) Delete(issuer, account string) error {
+	err := os.Remove(s.path(issuer, account))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package twofactor
+
+import (
+	"crypto"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestMemoryStoreRoundtrip(t *testing.T) {
+	store := NewMemoryStore()
+
+	otp, err := NewTOTP("info@sec51.com", "Sec51", crypto.SHA1, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Load(otp.issuer, otp.account); err != ErrNotFound {
+		t.Fatalf("Expected ErrNotFound before Save, got %v", err)
+	}
+
+	if err := store.Save(otp); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := store.Load(otp.issuer, otp.account)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.account != otp.account {
+		t.Error("Loaded Totp account does not match the saved one")
+	}
+
+	if err := store.Delete(otp.issuer, otp.account); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Load(otp.issuer, otp.account); err != ErrNotFound {
+		t.Fatalf("Expected ErrNotFound after Delete, got %v", err)
+	}
+}
+
+func TestFileStoreRoundtrip(t *testing.T) {
+	dir, err := os.MkdirTemp("", "twofactor-filestore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otp, err := NewTOTP("info@sec51.com", "Sec51", crypto.SHA256, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Save(otp); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := store.Load(otp.issuer, otp.account)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.label() != otp.label() {
+		t.Error("Loaded Totp label does not match the saved one")
+	}
+
+	if err := store.Delete(otp.issuer, otp.account); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Load(otp.issuer, otp.account); err != ErrNotFound {
+		t.Fatalf("Expected ErrNotFound after Delete, got %v", err)
+	}
+}
+
+func TestManagerValidate(t *testing.T) {
+	store := NewMemoryStore()
+	manager := NewManager(store)
+
+	otp, err := NewTOTP("info@sec51.com", "Sec51", crypto.SHA1, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Save(otp); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := otp.OTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.Validate(otp.issuer, otp.account, token); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.Validate(otp.issuer, otp.account, token); err != ErrUsedOTP {
+		t.Errorf("Expected ErrUsedOTP on replay through the Manager, got %v", err)
+	}
+}
+
+func TestManagerValidateConcurrentReplaySeenOnlyOnce(t *testing.T) {
+	store := NewMemoryStore()
+	manager := NewManager(store)
+
+	otp, err := NewTOTP("info@sec51.com", "Sec51", crypto.SHA1, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Save(otp); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := otp.OTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const attempts = 10
+	results := make([]error, attempts)
+
+	var wg sync.WaitGroup
+	wg.Add(attempts)
+	for i := 0; i < attempts; i++ {
+		go func(i int) {
+			defer wg.Done()
+			results[i] = manager.Validate(otp.issuer, otp.account, token)
+		}(i)
+	}
+	wg.Wait()
+
+	accepted := 0
+	for _, err := range results {
+		if err == nil {
+			accepted++
+		}
+	}
+
+	if accepted != 1 {
+		t.Errorf("Expected exactly one concurrent Validate call to accept the token, got %d", accepted)
+	}
+}
@@ -0,0 +1,519 @@
+// Package twofactor implements RFC 6238 (TOTP) one-time passwords, with the
+// serialization and rate-limiting helpers needed to run a 2FA enrollment and
+// verification flow on a server.
+package twofactor
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	mathrand "math/rand"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sec51/convert/bigendian"
+	"github.com/sec51/cryptoengine"
+)
+
+const (
+	// defaultStepSize is the RFC 6238 recommended time step, in seconds.
+	defaultStepSize = 30
+
+	// keySize is the length, in bytes, of the randomly generated shared secret.
+	keySize = 20
+
+	// legacyBackoffDuration is the fixed lockout window used by the
+	// package-level validBackoffTime helper, kept for callers that still
+	// rely on it directly. Validate itself uses the configurable,
+	// exponential-with-jitter policy below.
+	legacyBackoffDuration = 3 * time.Minute
+
+	// defaultMaxFailures is the default number of consecutive verification
+	// failures tolerated before Validate starts enforcing the backoff
+	// policy, regardless of the token.
+	defaultMaxFailures = 3
+
+	// defaultBackoffBase is the default base duration the exponential
+	// backoff policy grows from.
+	defaultBackoffBase = time.Second
+
+	// defaultBackoffCap is the default ceiling the exponential backoff
+	// policy is truncated to.
+	defaultBackoffCap = 10 * time.Minute
+
+	// maxJitter bounds the random jitter added on top of the computed
+	// backoff wait, so that many clients locked out at the same time don't
+	// all retry in lockstep.
+	maxJitter = time.Second
+)
+
+// Totp represents a time-based one-time password generator/validator, as
+// well as the state needed to detect replayed tokens and throttle brute
+// force attempts.
+type Totp struct {
+	key                       []byte
+	counter                   [8]byte
+	digits                    int
+	issuer                    string
+	account                   string
+	hashFunction              crypto.Hash
+	stepSize                  uint64
+	alphabet                  string
+	clientOffset              int
+	lastUsedOTP               string
+	lastVerificationTime      time.Time
+	totalVerificationFailures int
+	consecutiveFailures       int
+	backoffBase               time.Duration
+	backoffCap                time.Duration
+	maxFailures               int
+	clock                     Clock
+}
+
+// NewTOTP creates a new Totp for the given account/issuer pair, generating a
+// fresh random shared secret. The hash function must be registered and
+// available (callers typically blank-import crypto/sha1, crypto/sha256 or
+// crypto/sha512 for this).
+func NewTOTP(account, issuer string, hashFunction crypto.Hash, digits int, opts ...Option) (*Totp, error) {
+	if len(account) == 0 || len(issuer) == 0 || digits <= 0 {
+		return nil, ErrBadInput
+	}
+
+	if !hashFunction.Available() {
+		return nil, ErrBadInput
+	}
+
+	key, err := randomKey(keySize)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Totp{
+		key:          key,
+		digits:       digits,
+		issuer:       issuer,
+		account:      account,
+		hashFunction: hashFunction,
+		stepSize:     defaultStepSize,
+		backoffBase:  defaultBackoffBase,
+		backoffCap:   defaultBackoffCap,
+		maxFailures:  defaultMaxFailures,
+		clock:        defaultClock,
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t, nil
+}
+
+// SetBackoffPolicy configures the truncated exponential backoff Validate
+// enforces after consecutive failures: once maxFailures have accumulated,
+// the next Validate call is rejected with ErrRateLimited until
+// min(cap, base*2^(n-1)) plus jitter has elapsed since the last
+// verification attempt.
+func (t *Totp) SetBackoffPolicy(base, cap time.Duration, maxFailures int) {
+	t.backoffBase = base
+	t.backoffCap = cap
+	t.maxFailures = maxFailures
+}
+
+// NewTOTPWithAlphabet creates a new Totp like NewTOTP, but encodes tokens
+// using alphabet instead of plain decimal digits: the truncated HMAC value
+// is repeatedly reduced modulo len(alphabet) to pick `digits` characters
+// from it, as Steam Guard and a handful of other non-standard OTP schemes
+// do. alphabet must contain at least two distinct characters.
+func NewTOTPWithAlphabet(account, issuer string, hashFunction crypto.Hash, digits int, alphabet string, opts ...Option) (*Totp, error) {
+	if len(alphabet) < 2 {
+		return nil, ErrBadInput
+	}
+
+	otp, err := NewTOTP(account, issuer, hashFunction, digits, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	otp.alphabet = alphabet
+	return otp, nil
+}
+
+// steamAlphabet is the 26-character alphabet Steam Guard draws its 5
+// character codes from: it omits digits and letters that are easily
+// confused with one another (0/O, 1/I/L, etc).
+const steamAlphabet = "23456789BCDFGHJKMNPQRTVWXY"
+
+// NewSteamTOTP creates a Totp that generates Steam Guard codes: 5 characters
+// drawn from steamAlphabet, SHA1-based, on the standard 30 second step.
+func NewSteamTOTP(account, issuer string, opts ...Option) (*Totp, error) {
+	return NewTOTPWithAlphabet(account, issuer, crypto.SHA1, 5, steamAlphabet, opts...)
+}
+
+// OTP returns the current token for this Totp.
+func (t *Totp) OTP() (string, error) {
+	if t == nil || t.key == nil {
+		return "", ErrNilOtp
+	}
+
+	return calculateTOTP(t, 0), nil
+}
+
+// Validate checks the given token against the current time step, tolerating
+// a clock drift of one step in either direction. It guards against token
+// replay and, once maxFailures consecutive failures have accumulated, locks
+// out further attempts - returning ErrRateLimited - until the exponential
+// backoff policy's wait has elapsed since the last verification.
+func (t *Totp) Validate(token string) error {
+	if t == nil || t.key == nil {
+		return ErrNilOtp
+	}
+
+	if t.totalVerificationFailures >= t.effectiveMaxFailures() {
+		if remaining := t.backoffRemaining(); remaining > 0 {
+			return ErrRateLimited{Remaining: remaining}
+		}
+	}
+
+	hashedToken := hashToken(token)
+	if t.lastUsedOTP != "" && t.lastUsedOTP == hashedToken {
+		return ErrUsedOTP
+	}
+
+	for _, offset := range []int64{0, -1, 1} {
+		candidate := calculateTOTP(t, offset)
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+			t.clientOffset = int(offset)
+			t.lastUsedOTP = hashedToken
+			t.lastVerificationTime = t.clock.Now()
+			t.totalVerificationFailures = 0
+			t.consecutiveFailures = 0
+			return nil
+		}
+	}
+
+	t.lastVerificationTime = t.clock.Now()
+	if t.totalVerificationFailures < t.effectiveMaxFailures() {
+		t.totalVerificationFailures++
+	}
+	t.consecutiveFailures++
+
+	return ErrValidation
+}
+
+// effectiveMaxFailures returns the configured maxFailures, falling back to
+// defaultMaxFailures for a Totp that predates SetBackoffPolicy/was
+// deserialized from a blob that didn't carry one.
+func (t *Totp) effectiveMaxFailures() int {
+	if t.maxFailures <= 0 {
+		return defaultMaxFailures
+	}
+	return t.maxFailures
+}
+
+// backoffRemaining returns how much longer Validate must wait before
+// accepting another attempt, or zero if the backoff window has elapsed.
+func (t *Totp) backoffRemaining() time.Duration {
+	base := t.backoffBase
+	if base <= 0 {
+		base = defaultBackoffBase
+	}
+
+	ceiling := t.backoffCap
+	if ceiling <= 0 {
+		ceiling = defaultBackoffCap
+	}
+
+	// consecutiveFailures, unlike totalVerificationFailures, is never
+	// capped at maxFailures, so the wait keeps doubling every further
+	// failure instead of plateauing the moment rate limiting kicks in.
+	wait := base
+	for i := 1; i < t.consecutiveFailures && wait < ceiling; i++ {
+		wait *= 2
+	}
+	if wait > ceiling {
+		wait = ceiling
+	}
+	wait += time.Duration(mathrand.Int63n(int64(maxJitter) + 1))
+
+	elapsed := t.clock.Now().Sub(t.lastVerificationTime)
+	if elapsed >= wait {
+		return 0
+	}
+
+	return wait - elapsed
+}
+
+// validBackoffTime reports whether at least legacyBackoffDuration has
+// elapsed since the last verification attempt. It predates the
+// configurable exponential backoff policy Validate now enforces and is
+// kept as a simple, fixed-window helper for callers that want one.
+func validBackoffTime(lastVerificationTime time.Time) bool {
+	return time.Since(lastVerificationTime) >= legacyBackoffDuration
+}
+
+// getIntCounter returns the last counter value used to compute a token, as
+// a plain uint64.
+func (t *Totp) getIntCounter() uint64 {
+	return bigendian.FromUint64(t.counter)
+}
+
+// label returns the otpauth label for this Totp: the issuer and account,
+// each percent-encoded and joined by a literal colon.
+func (t *Totp) label() string {
+	return otpLabel(t.issuer, t.account)
+}
+
+// otpLabel builds the otpauth label shared by Totp and Hotp: the issuer and
+// account, each percent-encoded and joined by a literal colon.
+func otpLabel(issuer, account string) string {
+	return url.QueryEscape(issuer) + ":" + url.QueryEscape(account)
+}
+
+// url returns the otpauth:// provisioning URI for this Totp, suitable for
+// rendering as a QR code in an authenticator app.
+func (t *Totp) url() (string, error) {
+	if t == nil || t.key == nil || len(t.issuer) == 0 || len(t.account) == 0 {
+		return "", ErrNilOtp
+	}
+
+	u := url.URL{
+		Scheme: "otpauth",
+		Host:   "totp",
+		Path:   "/" + t.label(),
+	}
+
+	q := u.Query()
+	q.Set("secret", base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(t.key))
+	q.Set("issuer", t.issuer)
+	q.Set("algorithm", hashAlgorithmName(t.hashFunction))
+	q.Set("digits", strconv.Itoa(t.digits))
+	q.Set("period", strconv.FormatUint(t.stepSize, 10))
+	if t.alphabet == steamAlphabet {
+		q.Set("encoder", "steam")
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// totpGobData is the on-the-wire representation of a Totp, encrypted with
+// cryptoengine before being returned by ToBytes.
+type totpGobData struct {
+	Key                       []byte
+	Counter                   [8]byte
+	Digits                    int
+	Issuer                    string
+	Account                   string
+	HashFunction              crypto.Hash
+	StepSize                  uint64
+	Alphabet                  string
+	ClientOffset              int
+	LastUsedOTP               string
+	LastVerificationTime      time.Time
+	TotalVerificationFailures int
+	ConsecutiveFailures       int
+	BackoffBase               time.Duration
+	BackoffCap                time.Duration
+	MaxFailures               int
+}
+
+// ToBytes serializes and encrypts the Totp, keyed to its issuer, so it can
+// be persisted by the caller (typically alongside the account it belongs
+// to) and later restored with TOTPFromBytes.
+func (t *Totp) ToBytes() ([]byte, error) {
+	if t == nil || t.key == nil {
+		return nil, ErrNilOtp
+	}
+
+	var buf bytes.Buffer
+	data := totpGobData{
+		Key:                       t.key,
+		Counter:                   t.counter,
+		Digits:                    t.digits,
+		Issuer:                    t.issuer,
+		Account:                   t.account,
+		HashFunction:              t.hashFunction,
+		StepSize:                  t.stepSize,
+		Alphabet:                  t.alphabet,
+		ClientOffset:              t.clientOffset,
+		LastUsedOTP:               t.lastUsedOTP,
+		LastVerificationTime:      t.lastVerificationTime,
+		TotalVerificationFailures: t.totalVerificationFailures,
+		ConsecutiveFailures:       t.consecutiveFailures,
+		BackoffBase:               t.backoffBase,
+		BackoffCap:                t.backoffCap,
+		MaxFailures:               t.maxFailures,
+	}
+
+	if err := gob.NewEncoder(&buf).Encode(data); err != nil {
+		return nil, err
+	}
+
+	engine, err := cryptoengine.InitCryptoEngine(t.issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	plainMessage, err := cryptoengine.NewMessage(buf.String(), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedMessage, err := engine.NewEncryptedMessage(plainMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	return encryptedMessage.ToBytes()
+}
+
+// TOTPFromBytes decrypts and deserializes a Totp previously produced by
+// ToBytes. The issuer must match the one the Totp was encrypted with.
+func TOTPFromBytes(encryptedData []byte, issuer string) (*Totp, error) {
+	if len(issuer) == 0 {
+		return nil, ErrBadInput
+	}
+
+	engine, err := cryptoengine.InitCryptoEngine(issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	plainMessage, err := engine.Decrypt(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	var data totpGobData
+	if err := gob.NewDecoder(strings.NewReader(plainMessage.Text)).Decode(&data); err != nil {
+		return nil, err
+	}
+
+	stepSize := data.StepSize
+	if stepSize == 0 {
+		stepSize = defaultStepSize
+	}
+
+	return &Totp{
+		key:                       data.Key,
+		counter:                   data.Counter,
+		digits:                    data.Digits,
+		issuer:                    data.Issuer,
+		account:                   data.Account,
+		hashFunction:              data.HashFunction,
+		stepSize:                  stepSize,
+		alphabet:                  data.Alphabet,
+		clientOffset:              data.ClientOffset,
+		lastUsedOTP:               data.LastUsedOTP,
+		lastVerificationTime:      data.LastVerificationTime,
+		totalVerificationFailures: data.TotalVerificationFailures,
+		consecutiveFailures:       data.ConsecutiveFailures,
+		backoffBase:               data.BackoffBase,
+		backoffCap:                data.BackoffCap,
+		maxFailures:               data.MaxFailures,
+		clock:                     defaultClock,
+	}, nil
+}
+
+// calculateTOTP computes the token for the time step `offset` steps away
+// from the current one, recording the counter it used.
+func calculateTOTP(t *Totp, offset int64) string {
+	counter := uint64(int64(increment(t.clock.Now().Unix(), int64(t.stepSize))) + offset)
+	t.counter = bigendian.ToUint64(counter)
+
+	h := hmac.New(t.hashFunction.New, t.key)
+	if t.alphabet != "" {
+		return calculateAlphabetToken(t.counter[:], t.digits, h, t.alphabet)
+	}
+
+	return calculateToken(t.counter[:], t.digits, h)
+}
+
+// increment converts a unix timestamp into an RFC 6238 time-step counter.
+func increment(timestamp int64, stepSize int64) uint64 {
+	return uint64(timestamp) / uint64(stepSize)
+}
+
+// calculateToken implements the RFC 4226 dynamic truncation and decimal
+// formatting shared by HOTP and TOTP: it HMACs counter with h (already
+// keyed by the caller) and reduces the result to `digits` decimal digits.
+func calculateToken(counter []byte, digits int, h hash.Hash) string {
+	h.Write(counter)
+	sum := h.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	truncated := (uint32(sum[offset])&0x7f)<<24 |
+		uint32(sum[offset+1])<<16 |
+		uint32(sum[offset+2])<<8 |
+		uint32(sum[offset+3])
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, truncated%mod)
+}
+
+// calculateAlphabetToken performs the same RFC 4226 dynamic truncation as
+// calculateToken, but instead of reducing the result to decimal digits, it
+// repeatedly reduces the truncated value modulo len(alphabet) and emits the
+// corresponding character, `digits` times - the scheme Steam Guard uses for
+// its 5 character codes.
+func calculateAlphabetToken(counter []byte, digits int, h hash.Hash, alphabet string) string {
+	h.Write(counter)
+	sum := h.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	truncated := (uint32(sum[offset])&0x7f)<<24 |
+		uint32(sum[offset+1])<<16 |
+		uint32(sum[offset+2])<<8 |
+		uint32(sum[offset+3])
+
+	base := uint32(len(alphabet))
+	token := make([]byte, digits)
+	for i := 0; i < digits; i++ {
+		token[i] = alphabet[truncated%base]
+		truncated /= base
+	}
+
+	return string(token)
+}
+
+// hashToken returns a hex-encoded SHA-256 digest of token, used to detect
+// replay without keeping the plaintext token around.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomKey generates a cryptographically random shared secret of n bytes.
+func randomKey(n int) ([]byte, error) {
+	key := make([]byte, n)
+	if _, err := cryptorand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// hashAlgorithmName returns the otpauth algorithm= name for a crypto.Hash.
+func hashAlgorithmName(hashFunction crypto.Hash) string {
+	switch hashFunction {
+	case crypto.SHA256:
+		return "SHA256"
+	case crypto.SHA512:
+		return "SHA512"
+	default:
+		return "SHA1"
+	}
+}